@@ -0,0 +1,146 @@
+package signedauth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// KeyResolver resolves the access key extracted from a request's
+// Authorization header to the public key which should verify it (and the
+// string which was signed), so that callers can back it with a database,
+// a JWKS endpoint, or anything else. Its signature mirrors
+// AuthKeyManager.CheckHeader. publicKey may be a PEM-encoded key ([]byte
+// or string), an ed25519.PublicKey, or an *rsa.PublicKey.
+type KeyResolver interface {
+	CheckHeader(access string, req *http.Request) (publicKey interface{}, toSign string, authErr *AuthErr)
+	Authorize(access string) interface{}
+}
+
+// AsymmetricManager verifies request signatures using public-key
+// cryptography rather than a shared secret, so the signer and verifier
+// don't need to share one: the verifier only ever needs the sender's
+// published public key, which can be rotated independently. It supports
+// Ed25519 and RSA-PSS-SHA256, selected by the algorithm prefix of the
+// Authorization header, e.g. "ED25519 ak:sig" or "RS256 ak:sig".
+type AsymmetricManager struct{}
+
+// New returns a Gin middleware which verifies incoming requests against
+// manager, storing manager.Authorize's return value in the Gin context at
+// ContextKey on success.
+func (m *AsymmetricManager) New(manager KeyResolver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		auth := c.Request.Header.Get("Authorization")
+		if auth == "" {
+			abort(c, &AuthErr{401, errors.New("no Authorization header provided")})
+			return
+		}
+		algoAndRest := strings.SplitN(auth, " ", 2)
+		if len(algoAndRest) != 2 {
+			abort(c, &AuthErr{401, errors.New("malformed Authorization header")})
+			return
+		}
+		algo := algoAndRest[0]
+		accessAndSig := strings.SplitN(algoAndRest[1], ":", 2)
+		if len(accessAndSig) != 2 {
+			abort(c, &AuthErr{401, errors.New("malformed Authorization header")})
+			return
+		}
+		access, encodedSig := accessAndSig[0], accessAndSig[1]
+
+		signature, derr := base64.StdEncoding.DecodeString(encodedSig)
+		if derr != nil {
+			abort(c, &AuthErr{401, errors.New("could not decode signature")})
+			return
+		}
+
+		publicKey, toSign, authErr := manager.CheckHeader(access, c.Request)
+		if authErr != nil {
+			abort(c, authErr)
+			return
+		}
+
+		if verr := verifySignature(algo, publicKey, toSign, signature); verr != nil {
+			abort(c, &AuthErr{401, verr})
+			return
+		}
+
+		c.Set(ContextKey, manager.Authorize(access))
+		c.Next()
+	}
+}
+
+// verifySignature checks signature over toSign using the scheme named by
+// algo against publicKey, which may be a PEM-encoded key ([]byte or
+// string) or the raw key type the scheme expects: ed25519.PublicKey for
+// "ED25519", *rsa.PublicKey for "RS256".
+func verifySignature(algo string, publicKey interface{}, toSign string, signature []byte) error {
+	publicKey, err := resolvePublicKey(publicKey)
+	if err != nil {
+		return err
+	}
+	switch algo {
+	case "ED25519":
+		key, ok := publicKey.(ed25519.PublicKey)
+		if !ok {
+			return errors.New("public key is not an ed25519.PublicKey")
+		}
+		if !ed25519.Verify(key, []byte(toSign), signature) {
+			return errors.New("signature mismatch")
+		}
+		return nil
+	case "RS256":
+		key, ok := publicKey.(*rsa.PublicKey)
+		if !ok {
+			return errors.New("public key is not an *rsa.PublicKey")
+		}
+		hashed := sha256.Sum256([]byte(toSign))
+		if err := rsa.VerifyPSS(key, crypto.SHA256, hashed[:], signature, nil); err != nil {
+			return errors.New("signature mismatch")
+		}
+		return nil
+	default:
+		return errors.New("unsupported signing algorithm")
+	}
+}
+
+// resolvePublicKey normalizes publicKey into the concrete key type
+// verifySignature expects, decoding it from PEM first if it was handed to
+// us as raw bytes or a string.
+func resolvePublicKey(publicKey interface{}) (interface{}, error) {
+	switch key := publicKey.(type) {
+	case ed25519.PublicKey, *rsa.PublicKey:
+		return key, nil
+	case []byte:
+		return parsePEMPublicKey(key)
+	case string:
+		return parsePEMPublicKey([]byte(key))
+	default:
+		return nil, fmt.Errorf("unsupported public key type %T", publicKey)
+	}
+}
+
+// parsePEMPublicKey decodes a PEM block and parses it as a PKIX public
+// key, returning whatever concrete key type it encodes (e.g.
+// ed25519.PublicKey or *rsa.PublicKey).
+func parsePEMPublicKey(data []byte) (interface{}, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("could not decode PEM public key")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse PEM public key: %w", err)
+	}
+	return key, nil
+}
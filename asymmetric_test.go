@@ -0,0 +1,128 @@
+package signedauth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+type testKeyResolver struct {
+	publicKey interface{}
+	toSign    string
+}
+
+func (m *testKeyResolver) CheckHeader(access string, req *http.Request) (interface{}, string, *AuthErr) {
+	if access != "my_access_key" {
+		return nil, "", &AuthErr{403, fmt.Errorf("unknown access key %q", access)}
+	}
+	return m.publicKey, m.toSign, nil
+}
+
+func (m *testKeyResolver) Authorize(access string) interface{} {
+	return access
+}
+
+func runAsymmetric(manager *testKeyResolver, req *http.Request) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	m := &AsymmetricManager{}
+	router.GET("/", m.New(manager), func(c *gin.Context) {
+		c.String(200, "ok")
+	})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestAsymmetricManagerAcceptsValidEd25519Signature(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("could not generate key: %v", err)
+	}
+	toSign := "GET\n2016-01-01T00:00:00.000Z"
+	sig := ed25519.Sign(priv, []byte(toSign))
+
+	manager := &testKeyResolver{publicKey: pub, toSign: toSign}
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Authorization", "ED25519 my_access_key:"+base64.StdEncoding.EncodeToString(sig))
+
+	w := runAsymmetric(manager, req)
+	if w.Code != 200 {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestAsymmetricManagerAcceptsPEMEncodedEd25519Key(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("could not generate key: %v", err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		t.Fatalf("could not marshal public key: %v", err)
+	}
+	pemKey := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+
+	toSign := "GET\n2016-01-01T00:00:00.000Z"
+	sig := ed25519.Sign(priv, []byte(toSign))
+
+	manager := &testKeyResolver{publicKey: pemKey, toSign: toSign}
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Authorization", "ED25519 my_access_key:"+base64.StdEncoding.EncodeToString(sig))
+
+	w := runAsymmetric(manager, req)
+	if w.Code != 200 {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestAsymmetricManagerAcceptsValidRSASignature(t *testing.T) {
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("could not generate key: %v", err)
+	}
+	toSign := "GET\n2016-01-01T00:00:00.000Z"
+	hashed := sha256.Sum256([]byte(toSign))
+	sig, err := rsa.SignPSS(rand.Reader, priv, crypto.SHA256, hashed[:], nil)
+	if err != nil {
+		t.Fatalf("could not sign: %v", err)
+	}
+
+	manager := &testKeyResolver{publicKey: &priv.PublicKey, toSign: toSign}
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Authorization", "RS256 my_access_key:"+base64.StdEncoding.EncodeToString(sig))
+
+	w := runAsymmetric(manager, req)
+	if w.Code != 200 {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestAsymmetricManagerRejectsTamperedSignature(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("could not generate key: %v", err)
+	}
+	toSign := "GET\n2016-01-01T00:00:00.000Z"
+	sig := ed25519.Sign(priv, []byte(toSign+"tampered"))
+
+	manager := &testKeyResolver{publicKey: pub, toSign: toSign}
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Authorization", "ED25519 my_access_key:"+base64.StdEncoding.EncodeToString(sig))
+
+	w := runAsymmetric(manager, req)
+	if w.Code != 401 {
+		t.Fatalf("expected 401, got %d: %s", w.Code, w.Body.String())
+	}
+}
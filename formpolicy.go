@@ -0,0 +1,201 @@
+package signedauth
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxFormMemory bounds how much of a multipart POST policy upload
+// ParseMultipartForm will buffer in memory; anything past it (i.e. the
+// file part itself) spills to a temp file instead, so large uploads don't
+// need to be read into memory to verify their policy.
+const maxFormMemory = 1 << 20 // 1 MiB
+
+// PolicyCondition is a single condition of a POST policy document which
+// was checked and matched against the submitted form fields.
+type PolicyCondition struct {
+	// Field is the form field name the condition applies to, or
+	// "content-length-range" for the special byte-range condition.
+	Field string
+	// Value is the value the field was checked against (the exact value,
+	// the required prefix, or "min-max" for a length range).
+	Value string
+}
+
+// FormPolicyKeyManager is implemented by callers to plug their own
+// credential storage into FormPolicyManager. CheckHeader mirrors
+// SigV4KeyManager.CheckHeader; Authorize additionally receives the policy
+// conditions which were verified, so downstream handlers can trust them.
+type FormPolicyKeyManager interface {
+	CheckHeader(access, region, service string, req *http.Request) (secret string, authErr *AuthErr)
+	Authorize(access string, conditions []PolicyCondition) interface{}
+}
+
+// FormPolicyManager implements S3-style browser-based POST uploads: the
+// client submits a multipart form carrying a base64-encoded JSON policy
+// document and an SigV4 signature over it, rather than signing the
+// request itself -- which lets the form also carry an arbitrary file part
+// without that file needing to be part of what's signed.
+type FormPolicyManager struct{}
+
+type policyDocument struct {
+	Expiration string        `json:"expiration"`
+	Conditions []interface{} `json:"conditions"`
+}
+
+// New returns a Gin middleware which verifies the policy document of an
+// incoming multipart POST against manager, storing manager.Authorize's
+// return value in the Gin context at ContextKey on success.
+func (m *FormPolicyManager) New(manager FormPolicyKeyManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		req := c.Request
+		if err := req.ParseMultipartForm(maxFormMemory); err != nil {
+			abort(c, &AuthErr{400, fmt.Errorf("could not parse multipart form: %w", err)})
+			return
+		}
+		form := req.MultipartForm
+
+		if form.Value["x-amz-algorithm"] == nil || form.Value["x-amz-algorithm"][0] != "AWS4-HMAC-SHA256" {
+			abort(c, &AuthErr{401, errors.New("missing or unsupported x-amz-algorithm")})
+			return
+		}
+		credential := formValue(form, "x-amz-credential")
+		matches := credentialRe.FindStringSubmatch(credential)
+		if matches == nil {
+			abort(c, &AuthErr{401, errors.New("malformed x-amz-credential")})
+			return
+		}
+		access, dateStamp, region, service := matches[1], matches[2], matches[3], matches[4]
+
+		encodedPolicy := formValue(form, "policy")
+		if encodedPolicy == "" {
+			abort(c, &AuthErr{401, errors.New("missing policy field")})
+			return
+		}
+		signature := formValue(form, "x-amz-signature")
+		if signature == "" {
+			abort(c, &AuthErr{401, errors.New("missing x-amz-signature field")})
+			return
+		}
+
+		secret, authErr := manager.CheckHeader(access, region, service, req)
+		if authErr != nil {
+			abort(c, authErr)
+			return
+		}
+
+		signingKey := deriveSigningKey(secret, dateStamp, region, service)
+		expected := hex.EncodeToString(hmacSHA256(signingKey, encodedPolicy))
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(signature)) != 1 {
+			abort(c, &AuthErr{401, errors.New("signature mismatch")})
+			return
+		}
+
+		rawPolicy, derr := base64.StdEncoding.DecodeString(encodedPolicy)
+		if derr != nil {
+			abort(c, &AuthErr{400, errors.New("could not decode policy")})
+			return
+		}
+		var policy policyDocument
+		if err := json.Unmarshal(rawPolicy, &policy); err != nil {
+			abort(c, &AuthErr{400, fmt.Errorf("could not parse policy document: %w", err)})
+			return
+		}
+		expiration, eerr := time.Parse(time.RFC3339, policy.Expiration)
+		if eerr != nil {
+			abort(c, &AuthErr{400, errors.New("could not parse policy expiration")})
+			return
+		}
+		if time.Now().After(expiration) {
+			abort(c, &AuthErr{403, errors.New("policy has expired")})
+			return
+		}
+
+		conditions, cerr := checkConditions(policy.Conditions, form, req.ContentLength)
+		if cerr != nil {
+			abort(c, &AuthErr{403, cerr})
+			return
+		}
+
+		c.Set(ContextKey, manager.Authorize(access, conditions))
+		c.Next()
+	}
+}
+
+func formValue(form *multipart.Form, name string) string {
+	if values, ok := form.Value[name]; ok && len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// checkConditions verifies every condition in conditions against form and
+// contentLength, per the POST policy spec's three condition shapes: an
+// exact-match object, a ["starts-with", "$field", prefix] triple, and the
+// ["content-length-range", min, max] triple.
+func checkConditions(conditions []interface{}, form *multipart.Form, contentLength int64) ([]PolicyCondition, error) {
+	matched := make([]PolicyCondition, 0, len(conditions))
+	for _, raw := range conditions {
+		switch cond := raw.(type) {
+		case map[string]interface{}:
+			for field, want := range cond {
+				got := formValue(form, field)
+				if got != fmt.Sprint(want) {
+					return nil, fmt.Errorf("condition on %q did not match", field)
+				}
+				matched = append(matched, PolicyCondition{Field: field, Value: got})
+			}
+		case []interface{}:
+			if len(cond) != 3 {
+				return nil, errors.New("malformed policy condition")
+			}
+			op, _ := cond[0].(string)
+			switch op {
+			case "starts-with":
+				field := strings.TrimPrefix(fmt.Sprint(cond[1]), "$")
+				prefix := fmt.Sprint(cond[2])
+				got := formValue(form, field)
+				if !strings.HasPrefix(got, prefix) {
+					return nil, fmt.Errorf("condition on %q did not match its required prefix", field)
+				}
+				matched = append(matched, PolicyCondition{Field: field, Value: got})
+			case "content-length-range":
+				min, max := toInt64(cond[1]), toInt64(cond[2])
+				if contentLength < min || contentLength > max {
+					return nil, errors.New("content-length-range condition was not satisfied")
+				}
+				matched = append(matched, PolicyCondition{
+					Field: "content-length-range",
+					Value: strconv.FormatInt(min, 10) + "-" + strconv.FormatInt(max, 10),
+				})
+			default:
+				return nil, fmt.Errorf("unsupported policy condition operator %q", op)
+			}
+		default:
+			return nil, errors.New("malformed policy condition")
+		}
+	}
+	return matched, nil
+}
+
+func toInt64(v interface{}) int64 {
+	switch n := v.(type) {
+	case float64:
+		return int64(n)
+	case int64:
+		return n
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,182 @@
+package signedauth
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type testFormPolicyManager struct {
+	secret     string
+	conditions []PolicyCondition
+}
+
+func (m *testFormPolicyManager) CheckHeader(access, region, service string, req *http.Request) (string, *AuthErr) {
+	if access != "my_access_key" {
+		return "", &AuthErr{403, fmt.Errorf("unknown access key %q", access)}
+	}
+	return m.secret, nil
+}
+
+func (m *testFormPolicyManager) Authorize(access string, conditions []PolicyCondition) interface{} {
+	m.conditions = conditions
+	return access
+}
+
+func newFormPolicyRequest(t *testing.T, secret, region, service string, conditions []interface{}, fields map[string]string) *http.Request {
+	t.Helper()
+
+	when := time.Now().UTC()
+	dateStamp := when.Format("20060102")
+	credentialScope := fmt.Sprintf("%s/%s/%s/aws4_request", dateStamp, region, service)
+
+	policy := policyDocument{
+		Expiration: when.Add(time.Hour).Format(time.RFC3339),
+		Conditions: conditions,
+	}
+	rawPolicy, err := json.Marshal(policy)
+	if err != nil {
+		t.Fatalf("could not marshal policy: %v", err)
+	}
+	encodedPolicy := base64.StdEncoding.EncodeToString(rawPolicy)
+
+	key := deriveSigningKey(secret, dateStamp, region, service)
+	sig := hex.EncodeToString(hmacSHA256(key, encodedPolicy))
+
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	for name, value := range fields {
+		if err := w.WriteField(name, value); err != nil {
+			t.Fatalf("could not write field %q: %v", name, err)
+		}
+	}
+	if err := w.WriteField("policy", encodedPolicy); err != nil {
+		t.Fatalf("could not write policy field: %v", err)
+	}
+	if err := w.WriteField("x-amz-algorithm", "AWS4-HMAC-SHA256"); err != nil {
+		t.Fatalf("could not write x-amz-algorithm field: %v", err)
+	}
+	if err := w.WriteField("x-amz-credential", "my_access_key/"+credentialScope); err != nil {
+		t.Fatalf("could not write x-amz-credential field: %v", err)
+	}
+	if err := w.WriteField("x-amz-date", when.Format(iso8601)); err != nil {
+		t.Fatalf("could not write x-amz-date field: %v", err)
+	}
+	if err := w.WriteField("x-amz-signature", sig); err != nil {
+		t.Fatalf("could not write x-amz-signature field: %v", err)
+	}
+	part, err := w.CreateFormFile("file", "upload.bin")
+	if err != nil {
+		t.Fatalf("could not create file part: %v", err)
+	}
+	if _, err := part.Write([]byte("file contents")); err != nil {
+		t.Fatalf("could not write file part: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("could not close multipart writer: %v", err)
+	}
+
+	req := httptest.NewRequest("POST", "/upload", &body)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	return req
+}
+
+func runFormPolicy(manager *testFormPolicyManager, req *http.Request) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	m := &FormPolicyManager{}
+	router.POST("/upload", m.New(manager), func(c *gin.Context) {
+		c.String(200, "ok")
+	})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestFormPolicyManagerAcceptsValidPolicy(t *testing.T) {
+	conditions := []interface{}{
+		map[string]interface{}{"acl": "public-read"},
+		[]interface{}{"starts-with", "$key", "uploads/"},
+	}
+	manager := &testFormPolicyManager{secret: "my_secret"}
+	req := newFormPolicyRequest(t, manager.secret, "us-east-1", "s3", conditions, map[string]string{
+		"acl": "public-read",
+		"key": "uploads/my-file.png",
+	})
+
+	w := runFormPolicy(manager, req)
+	if w.Code != 200 {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if len(manager.conditions) != 2 {
+		t.Fatalf("expected 2 matched conditions, got %d", len(manager.conditions))
+	}
+}
+
+func TestFormPolicyManagerRejectsConditionMismatch(t *testing.T) {
+	conditions := []interface{}{
+		[]interface{}{"starts-with", "$key", "uploads/"},
+	}
+	manager := &testFormPolicyManager{secret: "my_secret"}
+	req := newFormPolicyRequest(t, manager.secret, "us-east-1", "s3", conditions, map[string]string{
+		"key": "not-allowed/my-file.png",
+	})
+
+	w := runFormPolicy(manager, req)
+	if w.Code != 403 {
+		t.Fatalf("expected 403 for a condition mismatch, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestFormPolicyManagerRejectsBadSignature(t *testing.T) {
+	manager := &testFormPolicyManager{secret: "my_secret"}
+	req := newFormPolicyRequest(t, "wrong_secret", "us-east-1", "s3", nil, nil)
+
+	w := runFormPolicy(manager, req)
+	if w.Code != 401 {
+		t.Fatalf("expected 401, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestFormPolicyManagerRejectsExpiredPolicy(t *testing.T) {
+	secret := "my_secret"
+	region, service := "us-east-1", "s3"
+	when := time.Now().UTC()
+	dateStamp := when.Format("20060102")
+	credentialScope := fmt.Sprintf("%s/%s/%s/aws4_request", dateStamp, region, service)
+
+	policy := policyDocument{
+		Expiration: when.Add(-time.Hour).Format(time.RFC3339),
+	}
+	rawPolicy, _ := json.Marshal(policy)
+	encodedPolicy := base64.StdEncoding.EncodeToString(rawPolicy)
+	key := deriveSigningKey(secret, dateStamp, region, service)
+	sig := hex.EncodeToString(hmacSHA256(key, encodedPolicy))
+
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	_ = w.WriteField("policy", encodedPolicy)
+	_ = w.WriteField("x-amz-algorithm", "AWS4-HMAC-SHA256")
+	_ = w.WriteField("x-amz-credential", "my_access_key/"+credentialScope)
+	_ = w.WriteField("x-amz-date", when.Format(iso8601))
+	_ = w.WriteField("x-amz-signature", sig)
+	_ = w.Close()
+
+	req := httptest.NewRequest("POST", "/upload", &body)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+
+	manager := &testFormPolicyManager{secret: secret}
+	resp := runFormPolicy(manager, req)
+	if resp.Code != 403 {
+		t.Fatalf("expected 403 for an expired policy, got %d: %s", resp.Code, resp.Body.String())
+	}
+}
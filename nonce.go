@@ -0,0 +1,101 @@
+package signedauth
+
+import (
+	"sync"
+	"time"
+)
+
+// NonceStore defends against replay attacks within a signature's freshness
+// window: Seen records nonce as observed for ttl and reports whether it had
+// already been seen. Implementations must be safe for concurrent use.
+type NonceStore interface {
+	Seen(nonce string, ttl time.Duration) (bool, error)
+}
+
+// MemoryNonceStore is an in-process NonceStore backed by a sync.Map. A
+// background goroutine periodically sweeps expired entries so memory use
+// stays bounded; call Stop when the store is no longer needed.
+type MemoryNonceStore struct {
+	seen sync.Map // map[string]time.Time (expiry)
+	stop chan struct{}
+	once sync.Once
+}
+
+// NewMemoryNonceStore returns a MemoryNonceStore which sweeps expired
+// nonces every sweepInterval.
+func NewMemoryNonceStore(sweepInterval time.Duration) *MemoryNonceStore {
+	s := &MemoryNonceStore{stop: make(chan struct{})}
+	go s.sweep(sweepInterval)
+	return s
+}
+
+// Seen reports whether nonce has already been recorded and not yet
+// expired, and records it (with expiry ttl from now) if not.
+func (s *MemoryNonceStore) Seen(nonce string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	expiry, loaded := s.seen.LoadOrStore(nonce, now.Add(ttl))
+	if !loaded {
+		return false, nil
+	}
+	if now.After(expiry.(time.Time)) {
+		// The previous entry expired; treat this as a fresh nonce.
+		s.seen.Store(nonce, now.Add(ttl))
+		return false, nil
+	}
+	return true, nil
+}
+
+// Stop terminates the background sweeper. It is safe to call more than
+// once.
+func (s *MemoryNonceStore) Stop() {
+	s.once.Do(func() { close(s.stop) })
+}
+
+func (s *MemoryNonceStore) sweep(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			now := time.Now()
+			s.seen.Range(func(key, value interface{}) bool {
+				if now.After(value.(time.Time)) {
+					s.seen.Delete(key)
+				}
+				return true
+			})
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// RedisClient is the subset of a Redis client's API which RedisNonceStore
+// needs. It mirrors the SetNX command most Redis client libraries expose
+// (e.g. github.com/go-redis/redis's *redis.Client), so a thin adapter over
+// one satisfies it without this package depending on any one of them
+// directly.
+type RedisClient interface {
+	SetNX(key string, value interface{}, expiration time.Duration) (bool, error)
+}
+
+// RedisNonceStore is a NonceStore backed by Redis, so that replay
+// protection holds across a fleet of instances rather than just one
+// process. A nonce is considered unseen if this instance was the one to
+// successfully SETNX its key.
+type RedisNonceStore struct {
+	Client RedisClient
+	// Prefix is prepended to every nonce before it's used as a Redis key,
+	// to namespace this store's keys away from the rest of the keyspace.
+	Prefix string
+}
+
+// Seen reports whether nonce has already been recorded in Redis, and
+// records it (with expiry ttl) if not.
+func (s *RedisNonceStore) Seen(nonce string, ttl time.Duration) (bool, error) {
+	ok, err := s.Client.SetNX(s.Prefix+nonce, 1, ttl)
+	if err != nil {
+		return false, err
+	}
+	return !ok, nil
+}
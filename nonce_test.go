@@ -0,0 +1,77 @@
+package signedauth
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMemoryNonceStoreRejectsReplay(t *testing.T) {
+	store := NewMemoryNonceStore(time.Minute)
+	defer store.Stop()
+
+	seen, err := store.Seen("abc", time.Minute)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if seen {
+		t.Fatal("first use of a nonce must not be reported as seen")
+	}
+
+	seen, err = store.Seen("abc", time.Minute)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !seen {
+		t.Fatal("replayed nonce must be reported as seen")
+	}
+}
+
+func TestMemoryNonceStoreExpires(t *testing.T) {
+	store := NewMemoryNonceStore(time.Minute)
+	defer store.Stop()
+
+	if _, err := store.Seen("abc", time.Millisecond); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	seen, err := store.Seen("abc", time.Minute)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if seen {
+		t.Fatal("expired nonce must not be reported as seen")
+	}
+}
+
+func TestMemoryNonceStoreConcurrentReplays(t *testing.T) {
+	store := NewMemoryNonceStore(time.Minute)
+	defer store.Stop()
+
+	const attempts = 100
+	var wg sync.WaitGroup
+	results := make([]bool, attempts)
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			seen, err := store.Seen("race", time.Minute)
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			results[i] = seen
+		}(i)
+	}
+	wg.Wait()
+
+	var accepted int
+	for _, seen := range results {
+		if !seen {
+			accepted++
+		}
+	}
+	if accepted != 1 {
+		t.Fatalf("expected exactly one concurrent attempt to win, got %d", accepted)
+	}
+}
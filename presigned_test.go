@@ -0,0 +1,88 @@
+package signedauth
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newPresignedRequest(t *testing.T, when time.Time, expiresIn int, secret, region, service string) *http.Request {
+	t.Helper()
+
+	dateStamp := when.Format("20060102")
+	amzDate := when.Format(iso8601)
+	credentialScope := fmt.Sprintf("%s/%s/%s/aws4_request", dateStamp, region, service)
+
+	query := url.Values{}
+	query.Set("X-Amz-Algorithm", "AWS4-HMAC-SHA256")
+	query.Set("X-Amz-Credential", "my_access_key/"+credentialScope)
+	query.Set("X-Amz-Date", amzDate)
+	query.Set("X-Amz-Expires", fmt.Sprintf("%d", expiresIn))
+	query.Set("X-Amz-SignedHeaders", "host")
+
+	req := httptest.NewRequest("GET", "/bucket/key?"+query.Encode(), nil)
+	req.Host = "example.com"
+
+	canonicalRequest := presignedCanonicalRequest(req, []string{"host"}, canonicalQuery(query), false)
+	sts := sigV4StringToSign(amzDate, credentialScope, canonicalRequest)
+	key := deriveSigningKey(secret, dateStamp, region, service)
+	sig := hex.EncodeToString(hmacSHA256(key, sts))
+
+	query.Set("X-Amz-Signature", sig)
+	req.URL.RawQuery = query.Encode()
+	req.RequestURI = req.URL.RequestURI()
+
+	return req
+}
+
+func runPresigned(manager *testSigV4Manager, req *http.Request) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	m := &PresignedManager{}
+	router.GET("/bucket/key", m.New(manager), func(c *gin.Context) {
+		c.String(200, "ok")
+	})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestPresignedManagerAcceptsValidSignature(t *testing.T) {
+	manager := &testSigV4Manager{secret: "my_secret"}
+	req := newPresignedRequest(t, time.Now().UTC(), 900, manager.secret, "us-east-1", "s3")
+
+	w := runPresigned(manager, req)
+	if w.Code != 200 {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestPresignedManagerRejectsExpiredURL(t *testing.T) {
+	manager := &testSigV4Manager{secret: "my_secret"}
+	req := newPresignedRequest(t, time.Now().UTC().Add(-20*time.Minute), 900, manager.secret, "us-east-1", "s3")
+
+	w := runPresigned(manager, req)
+	if w.Code != 403 {
+		t.Fatalf("expected 403 for expired presigned URL, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestPresignedManagerRejectsTamperedQuery(t *testing.T) {
+	manager := &testSigV4Manager{secret: "my_secret"}
+	req := newPresignedRequest(t, time.Now().UTC(), 900, manager.secret, "us-east-1", "s3")
+
+	q := req.URL.Query()
+	q.Set("X-Amz-Expires", "3600")
+	req.URL.RawQuery = q.Encode()
+
+	w := runPresigned(manager, req)
+	if w.Code != 401 {
+		t.Fatalf("expected 401 for a tampered query parameter, got %d: %s", w.Code, w.Body.String())
+	}
+}
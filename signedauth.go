@@ -0,0 +1,42 @@
+// Package signedauth provides Gin middleware for protecting routes with
+// signed-request schemes, such as the ones used by the AWS REST APIs.
+package signedauth
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ContextKey is the key under which an AuthKeyManager's Authorize return
+// value is stored in the Gin context.
+const ContextKey = "signedauth"
+
+// AuthErr couples an HTTP status code with the error which caused it, so
+// middleware can reply with the same error an AuthKeyManager returned.
+type AuthErr struct {
+	Code int
+	Err  error
+}
+
+// Error implements the error interface.
+func (a *AuthErr) Error() string {
+	return a.Err.Error()
+}
+
+// AuthKeyManager must be implemented by any type used to configure the
+// HMACManager middleware. CheckHeader resolves the access key found in the
+// request to a secret and to the string which the client signed; it may
+// also perform any additional validation of the request (e.g. verifying a
+// Date header) before doing so. Authorize is only called once the
+// signature has been verified, and its return value is stored in the Gin
+// context at ContextKey.
+type AuthKeyManager interface {
+	CheckHeader(access string, req *http.Request) (secret string, toSign string, authErr *AuthErr)
+	Authorize(access string) interface{}
+}
+
+// abort writes authErr to the Gin context and halts the handler chain.
+func abort(c *gin.Context, authErr *AuthErr) {
+	c.AbortWithError(authErr.Code, authErr.Err) // nolint: errcheck
+}